@@ -0,0 +1,180 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultLagRefreshInterval is used when AdminConfiguration.LagRefreshInterval is unset.
+const defaultLagRefreshInterval = 30 * time.Second
+
+// kafkaAdmin monitors consumer-group lag on topic and exposes partition
+// reassignments for it, on top of a sarama.ClusterAdmin and the client
+// used to look up log end offsets.
+type kafkaAdmin struct {
+	admin  sarama.ClusterAdmin
+	client sarama.Client
+	config AdminConfiguration
+	topic  string
+	lag    *prometheus.GaugeVec
+}
+
+func newKafkaAdmin(admin sarama.ClusterAdmin, client sarama.Client, config AdminConfiguration, topic string, lag *prometheus.GaugeVec) *kafkaAdmin {
+	return &kafkaAdmin{admin: admin, client: client, config: config, topic: topic, lag: lag}
+}
+
+// refreshLag recomputes consumer_lag for each configured consumer group on
+// topic's partitions.
+func (a *kafkaAdmin) refreshLag() error {
+	partitions, err := a.client.Partitions(a.topic)
+	if err != nil {
+		return fmt.Errorf("cannot list partitions for topic %q: %w", a.topic, err)
+	}
+
+	endOffsets := make(map[int32]int64, len(partitions))
+	for _, partition := range partitions {
+		offset, err := a.client.GetOffset(a.topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return fmt.Errorf("cannot get log end offset for partition %d: %w", partition, err)
+		}
+		endOffsets[partition] = offset
+	}
+
+	for _, group := range a.config.ConsumerGroups {
+		request := map[string][]int32{a.topic: partitions}
+		offsets, err := a.admin.ListConsumerGroupOffsets(group, request)
+		if err != nil {
+			return fmt.Errorf("cannot list offsets for consumer group %q: %w", group, err)
+		}
+		block, ok := offsets.Blocks[a.topic]
+		if !ok {
+			continue
+		}
+		for partition, endOffset := range endOffsets {
+			b, ok := block[partition]
+			if !ok || b.Offset < 0 {
+				continue
+			}
+			lag := endOffset - b.Offset
+			if lag < 0 {
+				lag = 0
+			}
+			a.lag.WithLabelValues(group, a.topic, fmt.Sprintf("%d", partition)).Set(float64(lag))
+		}
+	}
+	return nil
+}
+
+// reassignRequest is the body accepted by the reassignment endpoint.
+type reassignRequest struct {
+	Topic     string  `json:"topic"`
+	Partition int32   `json:"partition"`
+	Replicas  []int32 `json:"replicas"`
+}
+
+// reassignProgress is streamed back to the caller as ListPartitionReassignments is polled.
+type reassignProgress struct {
+	Done  bool   `json:"done"`
+	Error string `json:"error,omitempty"`
+}
+
+// reassignPollInterval is how often the reassignment endpoint polls
+// ListPartitionReassignments for completion.
+const reassignPollInterval = 2 * time.Second
+
+// alterPartitionReassignmentTimeout bounds how long the broker waits for
+// the reassignment to be accepted, per AlterPartitionReassignmentsRequest.
+const alterPartitionReassignmentTimeout = 60 * time.Second
+
+// alterPartitionReassignment triggers a reassignment of partition's
+// replicas on topic. sarama.ClusterAdmin.AlterPartitionReassignments takes
+// a slice of assignments indexed by partition number, which cannot express
+// a single partition's reassignment without either padding the slice or
+// reassigning every other partition too; build the request directly
+// instead.
+func (a *kafkaAdmin) alterPartitionReassignment(topic string, partition int32, replicas []int32) error {
+	controller, err := a.admin.Controller()
+	if err != nil {
+		return fmt.Errorf("cannot find Kafka controller: %w", err)
+	}
+	request := &sarama.AlterPartitionReassignmentsRequest{
+		TimeoutMs: int32(alterPartitionReassignmentTimeout.Milliseconds()),
+	}
+	request.AddBlock(topic, partition, replicas)
+	response, err := controller.AlterPartitionReassignments(request)
+	if err != nil {
+		return err
+	}
+	if response.ErrorCode != sarama.ErrNoError {
+		return response.ErrorCode
+	}
+	if partitionErrors, ok := response.Errors[topic]; ok {
+		if _, ok := partitionErrors[partition]; ok {
+			return fmt.Errorf("broker rejected reassignment of %s partition %d", topic, partition)
+		}
+	}
+	return nil
+}
+
+// reassignHandler triggers an AlterPartitionReassignments call for the
+// topic/partition/replicas in the request body, then streams one JSON
+// line per poll of ListPartitionReassignments until the reassignment
+// completes.
+func (a *kafkaAdmin) reassignHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.authenticate(r) {
+		http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+		return
+	}
+
+	var req reassignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "cannot decode request body", http.StatusBadRequest)
+		return
+	}
+	if req.Topic != a.topic {
+		http.Error(w, fmt.Sprintf("unknown topic %q, this endpoint only reassigns %q", req.Topic, a.topic), http.StatusBadRequest)
+		return
+	}
+	if err := a.alterPartitionReassignment(req.Topic, req.Partition, req.Replicas); err != nil {
+		http.Error(w, fmt.Sprintf("cannot alter partition reassignments: %s", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	flusher, _ := w.(http.Flusher)
+	ticker := time.NewTicker(reassignPollInterval)
+	defer ticker.Stop()
+	for {
+		status, err := a.admin.ListPartitionReassignments(req.Topic, []int32{req.Partition})
+		progress := reassignProgress{}
+		if err != nil {
+			progress.Error = err.Error()
+		} else if byPartition, ok := status[req.Topic]; !ok || byPartition[req.Partition] == nil {
+			progress.Done = true
+		}
+		encoded, _ := json.Marshal(progress)
+		w.Write(append(encoded, '\n'))
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if progress.Done || progress.Error != "" {
+			return
+		}
+		<-ticker.C
+	}
+}
+
+// authenticate checks the bearer token on r against
+// AdminConfiguration.ReassignToken. When ReassignToken is empty,
+// authentication is disabled.
+func (a *kafkaAdmin) authenticate(r *http.Request) bool {
+	if a.config.ReassignToken == "" {
+		return true
+	}
+	return r.Header.Get("Authorization") == "Bearer "+a.config.ReassignToken
+}
@@ -0,0 +1,114 @@
+package kafka
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestKafkaAdminAuthenticate(t *testing.T) {
+	cases := []struct {
+		name   string
+		token  string
+		header string
+		want   bool
+	}{
+		{"no token configured", "", "", true},
+		{"missing header", "secret", "", false},
+		{"wrong scheme", "secret", "secret", false},
+		{"wrong token", "secret", "Bearer wrong", false},
+		{"correct token", "secret", "Bearer secret", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a := &kafkaAdmin{config: AdminConfiguration{ReassignToken: c.token}}
+			req := httptest.NewRequest(http.MethodPost, "/reassign", nil)
+			if c.header != "" {
+				req.Header.Set("Authorization", c.header)
+			}
+			if got := a.authenticate(req); got != c.want {
+				t.Errorf("authenticate() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+// TestKafkaAdminReassignHandlerRejectsOtherTopics guards against the
+// endpoint reassigning partitions on a topic other than the one the admin
+// sub-component was configured for: a.admin is left nil here, so reaching
+// alterPartitionReassignment at all would panic the test.
+func TestKafkaAdminReassignHandlerRejectsOtherTopics(t *testing.T) {
+	a := &kafkaAdmin{topic: "flows"}
+	req := httptest.NewRequest(http.MethodPost, "/reassign", bytes.NewReader(
+		[]byte(`{"topic":"other","partition":0,"replicas":[1,2,3]}`)))
+	w := httptest.NewRecorder()
+	a.reassignHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("reassignHandler() status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+// TestKafkaAdminAlterPartitionReassignmentTargetsRequestedPartition guards
+// against reassigning partition 0 regardless of what was requested, which
+// happened when the ClusterAdmin.AlterPartitionReassignments wrapper (which
+// treats slice index as partition number) was fed a single-element slice.
+func TestKafkaAdminAlterPartitionReassignmentTargetsRequestedPartition(t *testing.T) {
+	seedBroker := sarama.NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	controllerBroker := sarama.NewMockBroker(t, 2)
+	defer controllerBroker.Close()
+
+	seedBroker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(controllerBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+			SetBroker(controllerBroker.Addr(), controllerBroker.BrokerID()),
+	})
+	controllerBroker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest":                 sarama.NewMockApiVersionsResponse(t),
+		"AlterPartitionReassignmentsRequest": sarama.NewMockAlterPartitionReassignmentsResponse(t),
+	})
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_4_0_0
+	admin, err := sarama.NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatalf("NewClusterAdmin() error:\n%+v", err)
+	}
+	defer admin.Close()
+
+	a := &kafkaAdmin{admin: admin}
+	if err := a.alterPartitionReassignment("mytopic", 3, []int32{1, 2, 3}); err != nil {
+		t.Fatalf("alterPartitionReassignment() error:\n%+v", err)
+	}
+
+	history := controllerBroker.History()
+	if len(history) == 0 {
+		t.Fatal("AlterPartitionReassignmentsRequest never reached the controller broker")
+	}
+	req, ok := history[len(history)-1].Request.(*sarama.AlterPartitionReassignmentsRequest)
+	if !ok {
+		t.Fatalf("unexpected request type %T", history[len(history)-1].Request)
+	}
+
+	// blocks is unexported; sarama gives no other way to inspect which
+	// partition AddBlock() targeted.
+	blocks := reflect.ValueOf(req).Elem().FieldByName("blocks")
+	topicBlocks := blocks.MapIndex(reflect.ValueOf("mytopic"))
+	if !topicBlocks.IsValid() {
+		t.Fatal("request does not target topic \"mytopic\"")
+	}
+	for _, partitionKey := range topicBlocks.MapKeys() {
+		if partitionKey.Int() == 0 {
+			t.Error("alterPartitionReassignment() targeted partition 0 instead of the requested partition 3")
+		}
+	}
+	if !topicBlocks.MapIndex(reflect.ValueOf(int32(3))).IsValid() {
+		t.Error("alterPartitionReassignment() did not target the requested partition 3")
+	}
+}
@@ -0,0 +1,27 @@
+package kafka
+
+import "github.com/Shopify/sarama"
+
+// fakeClusterAdmin implements just enough of sarama.ClusterAdmin to
+// exercise topicManager in tests.
+type fakeClusterAdmin struct {
+	sarama.ClusterAdmin
+	topics  map[string]bool
+	created map[string]bool
+}
+
+func (a *fakeClusterAdmin) ListTopics() (map[string]sarama.TopicDetail, error) {
+	out := make(map[string]sarama.TopicDetail, len(a.topics))
+	for topic := range a.topics {
+		out[topic] = sarama.TopicDetail{}
+	}
+	return out, nil
+}
+
+func (a *fakeClusterAdmin) CreateTopic(topic string, detail *sarama.TopicDetail, validateOnly bool) error {
+	if a.created == nil {
+		a.created = map[string]bool{}
+	}
+	a.created[topic] = true
+	return nil
+}
@@ -0,0 +1,187 @@
+package kafka
+
+import "time"
+
+// Configuration describes the configuration for the Kafka exporter.
+type Configuration struct {
+	// Brokers is the list of brokers to connect to.
+	Brokers []string
+	// Topic is the topic to use for exporting flows.
+	Topic string
+	// MaxMessageBytes is the maximum size of a message produced to Kafka.
+	// Defaults to sarama's own default (1000000) when unset.
+	MaxMessageBytes int
+	// CompressionCodec defines the compression codec to use for messages.
+	CompressionCodec CompressionCodec
+	// FlushBytes is the number of bytes to use to trigger a flush.
+	FlushBytes int
+	// FlushInterval is the frequency of flushes.
+	FlushInterval time.Duration
+	// UseTLS tells if TLS should be used to connect to brokers.
+	UseTLS bool
+	// SASL configures SASL authentication to brokers.
+	SASL SASLConfiguration
+	// TLS configures TLS beyond the system root CAs (custom CA, client certificates, ...).
+	TLS TLSConfiguration
+	// Sync enables synchronous production: Send() then blocks until the
+	// broker acknowledges the message and returns the resulting error, if
+	// any, instead of logging it asynchronously.
+	Sync bool
+	// RequiredAcks configures how many broker acknowledgments are required
+	// before a produce request is considered complete. Left nil, it
+	// defaults to waiting for the partition leader's acknowledgment; set
+	// it to a pointer to AcksNone to explicitly opt out of acknowledgment.
+	RequiredAcks *RequiredAcks
+	// Retries is the number of times to retry sending a message before
+	// giving up.
+	Retries int
+	// Idempotent enables idempotent production, in both sync and async
+	// modes. It requires RequiredAcks set to wait for all replicas; New()
+	// fails otherwise.
+	Idempotent bool
+	// FlushTimeout bounds how long Stop() waits for the producer to drain
+	// pending messages before giving up.
+	FlushTimeout time.Duration
+	// TopicTemplate is a Go text/template evaluated for each flow to
+	// compute its destination topic. It is executed against a value
+	// exposing the exporter host, parsed flow metadata (see
+	// FlowMetadata) and KeyHashBucket, the hash bucket of the Kafka
+	// message key. Leave empty to always use Topic.
+	TopicTemplate string
+	// TopicDefault is the topic to use when TopicTemplate evaluates to an
+	// empty string.
+	TopicDefault string
+	// AutoCreateTopics, when set, enables automatic creation of topics
+	// referenced by TopicTemplate that do not exist yet on the brokers.
+	AutoCreateTopics *AutoCreateTopicsConfiguration
+	// Encoding selects how payloads passed to Send() are wrapped before
+	// being produced to Kafka. Defaults to EncodingRaw.
+	Encoding EncoderType
+	// SchemaRegistry configures schema registration for the protobuf and
+	// avro-confluent encodings.
+	SchemaRegistry SchemaRegistryConfiguration
+	// Admin configures the admin sub-component, monitoring consumer-group
+	// lag and exposing a partition-reassignment endpoint for Topic. Leave
+	// nil to disable it.
+	Admin *AdminConfiguration
+}
+
+// AdminConfiguration describes the Kafka admin sub-component.
+type AdminConfiguration struct {
+	// ConsumerGroups is the set of consumer groups to monitor lag for, on
+	// Topic.
+	ConsumerGroups []string
+	// LagRefreshInterval is how often consumer-group lag is refreshed.
+	// Defaults to 30s.
+	LagRefreshInterval time.Duration
+	// ReassignPath is the HTTP path the partition-reassignment endpoint
+	// is mounted on.
+	ReassignPath string
+	// ReassignToken is the bearer token required to authenticate requests
+	// to ReassignPath. Requests without it are rejected. Required whenever
+	// ReassignPath is set, so the endpoint can never be mounted open.
+	ReassignToken string
+}
+
+// EncoderType selects how Send() payloads are wrapped before being
+// produced to Kafka.
+type EncoderType string
+
+const (
+	// EncodingRaw sends payloads unmodified.
+	EncodingRaw EncoderType = "raw"
+	// EncodingProtobuf wraps payloads in the Confluent wire format, using
+	// a schema registered as a protobuf schema.
+	EncodingProtobuf EncoderType = "protobuf"
+	// EncodingAvroConfluent wraps payloads in the Confluent wire format,
+	// using a schema registered as an Avro schema.
+	EncodingAvroConfluent EncoderType = "avro-confluent"
+)
+
+// SchemaRegistryConfiguration describes how to register the schema for the
+// protobuf and avro-confluent encodings with a Confluent-compatible schema
+// registry.
+type SchemaRegistryConfiguration struct {
+	// URL is the base URL of the schema registry (e.g. http://registry:8081).
+	URL string
+	// Schema is the schema definition to register (a protobuf descriptor
+	// or an Avro schema, as JSON text, depending on Encoding).
+	Schema string
+	// Required tells whether New() should fail when the registry is
+	// unreachable. When false, the component falls back to EncodingRaw.
+	Required bool
+	// Timeout bounds the HTTP calls made to the registry.
+	Timeout time.Duration
+}
+
+// AutoCreateTopicsConfiguration describes how topics referenced by
+// TopicTemplate should be created when they do not already exist.
+type AutoCreateTopicsConfiguration struct {
+	// Partitions is the number of partitions for newly created topics.
+	Partitions int32
+	// ReplicationFactor is the replication factor for newly created topics.
+	ReplicationFactor int16
+	// ConfigEntries are extra topic-level configuration entries to set on
+	// newly created topics (e.g. "retention.ms").
+	ConfigEntries map[string]*string
+}
+
+// RequiredAcks represents the number of broker acknowledgments required
+// before a produce request is considered complete.
+type RequiredAcks int16
+
+const (
+	// AcksNone means the producer does not wait for any acknowledgment
+	// from the broker at all.
+	AcksNone RequiredAcks = 0
+	// AcksLocal means the producer waits for the partition leader's
+	// acknowledgment only.
+	AcksLocal RequiredAcks = 1
+	// AcksAll means the producer waits for acknowledgment from the
+	// partition leader and all its in-sync replicas.
+	AcksAll RequiredAcks = -1
+)
+
+// CompressionCodec represents the codec used to compress messages before sending them to Kafka.
+type CompressionCodec int
+
+// SASLMechanism represents a SASL mechanism supported to authenticate to Kafka brokers.
+type SASLMechanism string
+
+const (
+	// SASLMechanismPlain authenticates using a plaintext username/password.
+	SASLMechanismPlain SASLMechanism = "PLAIN"
+	// SASLMechanismSCRAMSHA256 authenticates using SCRAM-SHA-256.
+	SASLMechanismSCRAMSHA256 SASLMechanism = "SCRAM-SHA-256"
+	// SASLMechanismSCRAMSHA512 authenticates using SCRAM-SHA-512.
+	SASLMechanismSCRAMSHA512 SASLMechanism = "SCRAM-SHA-512"
+	// OAUTHBEARER is not supported yet: it requires an
+	// sarama.AccessTokenProvider, which Configuration has no way to
+	// supply. Configuring it fails validation in configureSASL.
+)
+
+// SASLConfiguration describes SASL authentication settings for the Kafka exporter.
+type SASLConfiguration struct {
+	// Enabled tells whether SASL authentication should be used.
+	Enabled bool
+	// Mechanism is the SASL mechanism to use. Defaults to PLAIN.
+	Mechanism SASLMechanism
+	// Username is the SASL username.
+	Username string
+	// Password is the SASL password.
+	Password string
+}
+
+// TLSConfiguration describes TLS settings for the Kafka exporter, used when UseTLS is enabled.
+type TLSConfiguration struct {
+	// CACertFile is an optional path to a PEM-encoded CA certificate bundle to trust in addition to the system roots.
+	CACertFile string
+	// ClientCertFile is an optional path to a PEM-encoded client certificate, for mTLS.
+	ClientCertFile string
+	// ClientKeyFile is an optional path to the PEM-encoded private key matching ClientCertFile.
+	ClientKeyFile string
+	// SkipVerify disables server certificate verification. Only use this for testing.
+	SkipVerify bool
+	// ServerName overrides the server name used to verify the broker certificate.
+	ServerName string
+}
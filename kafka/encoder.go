@@ -0,0 +1,67 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Encoder wraps flow payloads before they are produced to Kafka.
+type Encoder interface {
+	Encode(payload []byte) []byte
+}
+
+// rawEncoder sends payloads unmodified.
+type rawEncoder struct{}
+
+func (rawEncoder) Encode(payload []byte) []byte {
+	return payload
+}
+
+// confluentEncoder wraps payloads in the Confluent wire format: a leading
+// magic byte (0x00) followed by the 4-byte big-endian schema ID and the
+// payload itself.
+type confluentEncoder struct {
+	schemaID int32
+}
+
+func (e *confluentEncoder) Encode(payload []byte) []byte {
+	out := make([]byte, 5+len(payload))
+	out[0] = 0x00
+	binary.BigEndian.PutUint32(out[1:5], uint32(e.schemaID))
+	copy(out[5:], payload)
+	return out
+}
+
+// setupEncoder builds the Encoder to use for Send(), registering a schema
+// with the schema registry when Encoding requires one. If the registry is
+// unreachable and SchemaRegistry.Required is false, it falls back to
+// EncodingRaw.
+func (c *Component) setupEncoder(configuration Configuration) (Encoder, error) {
+	var schemaType string
+	switch configuration.Encoding {
+	case "", EncodingRaw:
+		return rawEncoder{}, nil
+	case EncodingProtobuf:
+		schemaType = "PROTOBUF"
+	case EncodingAvroConfluent:
+		schemaType = "AVRO"
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", configuration.Encoding)
+	}
+
+	topic := configuration.Topic
+	if topic == "" {
+		topic = configuration.TopicDefault
+	}
+	client := newSchemaRegistryClient(configuration.SchemaRegistry)
+	id, err := client.register(topic, configuration.SchemaRegistry.Schema, schemaType)
+	if err != nil {
+		if configuration.SchemaRegistry.Required {
+			return nil, fmt.Errorf("cannot register schema: %w", err)
+		}
+		c.r.Err(err).Msg("cannot register schema, falling back to raw encoding")
+		return rawEncoder{}, nil
+	}
+	c.metrics.schemaID.Set(float64(id))
+	return &confluentEncoder{schemaID: id}, nil
+}
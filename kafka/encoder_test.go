@@ -0,0 +1,73 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"akvorado/reporter"
+)
+
+func TestConfluentEncoderWireFormat(t *testing.T) {
+	e := &confluentEncoder{schemaID: 42}
+	out := e.Encode([]byte("hello"))
+	if out[0] != 0x00 {
+		t.Fatalf("Encode() magic byte = %#x, want 0x00", out[0])
+	}
+	if got := binary.BigEndian.Uint32(out[1:5]); got != 42 {
+		t.Errorf("Encode() schema ID = %d, want 42", got)
+	}
+	if string(out[5:]) != "hello" {
+		t.Errorf("Encode() payload = %q, want %q", out[5:], "hello")
+	}
+}
+
+func TestSchemaRegistryClientRegister(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/subjects/flows-value/versions" {
+			t.Errorf("unexpected request path %q", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(registerSchemaResponse{ID: 7})
+	}))
+	defer server.Close()
+
+	client := newSchemaRegistryClient(SchemaRegistryConfiguration{URL: server.URL})
+	id, err := client.register("flows", `{"type":"record","name":"Flow","fields":[]}`, "AVRO")
+	if err != nil {
+		t.Fatalf("register() error:\n%+v", err)
+	}
+	if id != 7 {
+		t.Errorf("register() id = %d, want 7", id)
+	}
+}
+
+func TestSetupEncoderFallsBackToRawWhenRegistryUnreachable(t *testing.T) {
+	c := &Component{r: reporter.NewMock(t)}
+	c.initMetrics()
+	encoder, err := c.setupEncoder(Configuration{
+		Topic:          "flows",
+		Encoding:       EncodingAvroConfluent,
+		SchemaRegistry: SchemaRegistryConfiguration{URL: "http://127.0.0.1:0", Required: false},
+	})
+	if err != nil {
+		t.Fatalf("setupEncoder() error:\n%+v", err)
+	}
+	if _, ok := encoder.(rawEncoder); !ok {
+		t.Errorf("setupEncoder() = %T, want rawEncoder", encoder)
+	}
+}
+
+func TestSetupEncoderFailsWhenRegistryRequired(t *testing.T) {
+	c := &Component{r: reporter.NewMock(t)}
+	c.initMetrics()
+	_, err := c.setupEncoder(Configuration{
+		Topic:          "flows",
+		Encoding:       EncodingAvroConfluent,
+		SchemaRegistry: SchemaRegistryConfiguration{URL: "http://127.0.0.1:0", Required: true},
+	})
+	if err == nil {
+		t.Error("setupEncoder() did not error when registry is required and unreachable")
+	}
+}
@@ -0,0 +1,53 @@
+package kafka
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metrics holds the various metrics exported by the Kafka component.
+type metrics struct {
+	bytesSent    *prometheus.CounterVec
+	messagesSent *prometheus.CounterVec
+	errors       *prometheus.CounterVec
+	schemaID     prometheus.Gauge
+	consumerLag  *prometheus.GaugeVec
+}
+
+// initMetrics initializes the metrics for the Kafka component.
+func (c *Component) initMetrics() {
+	c.metrics.bytesSent = promauto.With(c.r).NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bytes_sent",
+			Help: "Number of bytes sent to Kafka.",
+		},
+		[]string{"exporter"},
+	)
+	c.metrics.messagesSent = promauto.With(c.r).NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messages_sent",
+			Help: "Number of messages sent to Kafka.",
+		},
+		[]string{"exporter"},
+	)
+	c.metrics.errors = promauto.With(c.r).NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "errors",
+			Help: "Number of errors while sending messages to Kafka.",
+		},
+		[]string{"error"},
+	)
+	c.metrics.schemaID = promauto.With(c.r).NewGauge(
+		prometheus.GaugeOpts{
+			Name: "schema_id",
+			Help: "ID of the schema registered with the schema registry, when using the protobuf or avro-confluent encoding.",
+		},
+	)
+	c.metrics.consumerLag = promauto.With(c.r).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "consumer_lag",
+			Help: "Lag, in number of messages, of a consumer group on a topic partition.",
+		},
+		[]string{"group", "topic", "partition"},
+	)
+}
@@ -0,0 +1,33 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+)
+
+func TestAsyncProducerSendMessageDoesNotBlockOnBroker(t *testing.T) {
+	mock := mocks.NewAsyncProducer(t, sarama.NewConfig())
+	defer mock.Close()
+	mock.ExpectInputAndSucceed()
+
+	p := &asyncProducer{mock}
+	if _, _, err := p.SendMessage(&sarama.ProducerMessage{Topic: "test"}); err != nil {
+		t.Fatalf("SendMessage() error:\n%+v", err)
+	}
+}
+
+func TestSyncProducerSendMessagePropagatesError(t *testing.T) {
+	mock := mocks.NewSyncProducer(t, sarama.NewConfig())
+	defer mock.Close()
+	wantErr := errors.New("broker unavailable")
+	mock.ExpectSendMessageAndFail(wantErr)
+
+	var p kafkaProducer = mock
+	_, _, err := p.SendMessage(&sarama.ProducerMessage{Topic: "test"})
+	if !errors.Is(err, wantErr) {
+		t.Errorf("SendMessage() error = %v, want %v", err, wantErr)
+	}
+}
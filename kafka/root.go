@@ -2,10 +2,10 @@
 package kafka
 
 import (
-	"crypto/tls"
-	"crypto/x509"
 	"fmt"
+	"net/http"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/Shopify/sarama"
@@ -13,6 +13,7 @@ import (
 	"gopkg.in/tomb.v2"
 
 	"akvorado/daemon"
+	akvhttp "akvorado/http"
 	"akvorado/reporter"
 )
 
@@ -24,51 +25,147 @@ type Component struct {
 	config Configuration
 
 	kafkaConfig         *sarama.Config
-	kafkaProducer       sarama.AsyncProducer
-	createKafkaProducer func() (sarama.AsyncProducer, error)
+	kafkaProducer       kafkaProducer
+	createKafkaProducer func() (kafkaProducer, error)
 	metrics             metrics
+
+	topicTemplate *template.Template
+	topics        *topicManager
+	encoder       Encoder
+	admin         *kafkaAdmin
 }
 
 // Dependencies define the dependencies of the Kafka exporter.
 type Dependencies struct {
 	Daemon daemon.Component
+	// HTTP is only required when Configuration.Admin is set, to mount
+	// the partition-reassignment endpoint.
+	HTTP akvhttp.Component
 }
 
 // New creates a new HTTP component.
 func New(reporter *reporter.Reporter, configuration Configuration, dependencies Dependencies) (*Component, error) {
 	// Build Kafka configuration
 	kafkaConfig := sarama.NewConfig()
-	kafkaConfig.Producer.MaxMessageBytes = configuration.MaxMessageBytes
+	if configuration.MaxMessageBytes > 0 {
+		kafkaConfig.Producer.MaxMessageBytes = configuration.MaxMessageBytes
+	}
 	kafkaConfig.Producer.Compression = sarama.CompressionCodec(configuration.CompressionCodec)
-	kafkaConfig.Producer.Return.Successes = false
+	kafkaConfig.Producer.Return.Successes = configuration.Sync
 	kafkaConfig.Producer.Return.Errors = true
 	kafkaConfig.Producer.Flush.Bytes = configuration.FlushBytes
 	kafkaConfig.Producer.Flush.Frequency = configuration.FlushInterval
 	kafkaConfig.Producer.Partitioner = sarama.NewHashPartitioner
+	if configuration.RequiredAcks != nil {
+		kafkaConfig.Producer.RequiredAcks = sarama.RequiredAcks(*configuration.RequiredAcks)
+	} else {
+		kafkaConfig.Producer.RequiredAcks = sarama.WaitForLocal
+	}
+	if configuration.Retries > 0 {
+		kafkaConfig.Producer.Retry.Max = configuration.Retries
+	}
+	kafkaConfig.Producer.Idempotent = configuration.Idempotent
+	if configuration.Idempotent {
+		kafkaConfig.Net.MaxOpenRequests = 1
+	}
 	if configuration.UseTLS {
-		rootCAs, err := x509.SystemCertPool()
+		tlsConfig, err := buildTLSConfig(configuration.TLS)
 		if err != nil {
 			return nil, fmt.Errorf("cannot initialize TLS: %w", err)
 		}
 		kafkaConfig.Net.TLS.Enable = true
-		kafkaConfig.Net.TLS.Config = &tls.Config{RootCAs: rootCAs}
+		kafkaConfig.Net.TLS.Config = tlsConfig
+	}
+	if configuration.SASL.Enabled {
+		if err := configureSASL(kafkaConfig, configuration.SASL); err != nil {
+			return nil, fmt.Errorf("cannot initialize SASL: %w", err)
+		}
 	}
 	if err := kafkaConfig.Validate(); err != nil {
 		return nil, fmt.Errorf("cannot validate Kafka configuration: %w", err)
 	}
 
+	var topicTemplate *template.Template
+	if configuration.TopicTemplate != "" {
+		tmpl, err := template.New("topic").Parse(configuration.TopicTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse topic template: %w", err)
+		}
+		topicTemplate = tmpl
+	}
+
 	c := Component{
 		r:      reporter,
 		d:      &dependencies,
 		config: configuration,
 
-		kafkaConfig: kafkaConfig,
+		kafkaConfig:   kafkaConfig,
+		topicTemplate: topicTemplate,
+	}
+
+	// Close whichever ClusterAdmin/Client handles were already opened if
+	// we bail out before New() succeeds; they would otherwise leak, since
+	// Stop() is never reached.
+	ready := false
+	var topicsAdmin sarama.ClusterAdmin
+	defer func() {
+		if ready {
+			return
+		}
+		if topicsAdmin != nil {
+			topicsAdmin.Close()
+		}
+		if c.admin != nil {
+			c.admin.admin.Close()
+			c.admin.client.Close()
+		}
+	}()
+
+	if topicTemplate != nil {
+		admin, err := sarama.NewClusterAdmin(configuration.Brokers, kafkaConfig)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create Kafka cluster admin: %w", err)
+		}
+		topicsAdmin = admin
+		c.topics = newTopicManager(admin, configuration.AutoCreateTopics)
 	}
 	c.initMetrics()
-	c.createKafkaProducer = func() (sarama.AsyncProducer, error) {
-		return sarama.NewAsyncProducer(c.config.Brokers, c.kafkaConfig)
+	if configuration.Admin != nil {
+		if configuration.Admin.ReassignPath != "" && configuration.Admin.ReassignToken == "" {
+			return nil, fmt.Errorf("ReassignToken is required when ReassignPath is set")
+		}
+		adminClient, err := sarama.NewClusterAdmin(configuration.Brokers, kafkaConfig)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create Kafka admin cluster admin: %w", err)
+		}
+		client, err := sarama.NewClient(configuration.Brokers, kafkaConfig)
+		if err != nil {
+			adminClient.Close()
+			return nil, fmt.Errorf("cannot create Kafka admin client: %w", err)
+		}
+		adminConfig := *configuration.Admin
+		if adminConfig.LagRefreshInterval <= 0 {
+			adminConfig.LagRefreshInterval = defaultLagRefreshInterval
+		}
+		c.admin = newKafkaAdmin(adminClient, client, adminConfig, configuration.Topic, c.metrics.consumerLag)
+	}
+	encoder, err := c.setupEncoder(configuration)
+	if err != nil {
+		return nil, err
+	}
+	c.encoder = encoder
+	c.createKafkaProducer = func() (kafkaProducer, error) {
+		if c.config.Sync {
+			return sarama.NewSyncProducer(c.config.Brokers, c.kafkaConfig)
+		}
+		producer, err := sarama.NewAsyncProducer(c.config.Brokers, c.kafkaConfig)
+		if err != nil {
+			return nil, err
+		}
+		return &asyncProducer{producer}, nil
 	}
 	c.d.Daemon.Track(&c.t, "kafka")
+	ready = true
 	return &c, nil
 }
 
@@ -79,14 +176,70 @@ func (c *Component) Start() error {
 	if err != nil {
 		c.r.Err(err).
 			Str("brokers", strings.Join(c.config.Brokers, ",")).
-			Msg("unable to create async producer")
-		return fmt.Errorf("unable to create Kafka async producer: %w", err)
+			Msg("unable to create producer")
+		return fmt.Errorf("unable to create Kafka producer: %w", err)
 	}
 	c.kafkaProducer = kafkaProducer
 
+	if c.topics != nil {
+		if err := c.topics.refresh(); err != nil {
+			c.r.Err(err).Msg("unable to refresh Kafka topic list")
+		}
+		c.t.Go(func() error {
+			ticker := time.NewTicker(topicRefreshInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-c.t.Dying():
+					return nil
+				case <-ticker.C:
+					if err := c.topics.refresh(); err != nil {
+						c.r.Err(err).Msg("unable to refresh Kafka topic list")
+					}
+				}
+			}
+		})
+	}
+
+	if c.admin != nil {
+		if c.config.Admin.ReassignPath != "" {
+			c.d.HTTP.AddHandler(c.config.Admin.ReassignPath, http.HandlerFunc(c.admin.reassignHandler))
+		}
+		if err := c.admin.refreshLag(); err != nil {
+			c.r.Err(err).Msg("unable to refresh Kafka consumer group lag")
+		}
+		c.t.Go(func() error {
+			ticker := time.NewTicker(c.admin.config.LagRefreshInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-c.t.Dying():
+					return nil
+				case <-ticker.C:
+					if err := c.admin.refreshLag(); err != nil {
+						c.r.Err(err).Msg("unable to refresh Kafka consumer group lag")
+					}
+				}
+			}
+		})
+	}
+
+	// In sync mode (or with a producer that isn't *asyncProducer, e.g. a
+	// test mock), delivery errors are returned directly by Send(), there
+	// is no background error channel to drain. We still need a goroutine
+	// tracked on c.t, otherwise c.t.Wait() in Stop() blocks forever: it
+	// never unblocks unless at least one has been started with c.t.Go().
+	ap, ok := kafkaProducer.(*asyncProducer)
+	if !ok {
+		c.t.Go(func() error {
+			<-c.t.Dying()
+			return nil
+		})
+		return nil
+	}
+
 	// Main loop
 	c.t.Go(func() error {
-		defer kafkaProducer.Close()
 		defer c.kafkaConfig.MetricRegistry.UnregisterAll()
 		errLimiter := rate.NewLimiter(rate.Every(10*time.Second), 3)
 		for {
@@ -94,7 +247,7 @@ func (c *Component) Start() error {
 			case <-c.t.Dying():
 				c.r.Debug().Msg("stop error logger")
 				return nil
-			case msg := <-kafkaProducer.Errors():
+			case msg := <-ap.Errors():
 				c.metrics.errors.WithLabelValues(msg.Error()).Inc()
 				if errLimiter.Allow() {
 					c.r.Err(msg.Err).
@@ -109,20 +262,64 @@ func (c *Component) Start() error {
 	return nil
 }
 
-// Stop stops the Kafka component
+// Stop stops the Kafka component, giving the producer up to
+// Configuration.FlushTimeout to drain pending messages.
 func (c *Component) Stop() error {
 	c.t.Kill(nil)
-	return c.t.Wait()
+	err := c.t.Wait()
+	if c.kafkaProducer == nil {
+		return err
+	}
+	if closeErr := c.closeProducer(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if c.topics != nil {
+		c.topics.admin.Close()
+	}
+	if c.admin != nil {
+		c.admin.admin.Close()
+		c.admin.client.Close()
+	}
+	return err
+}
+
+func (c *Component) closeProducer() error {
+	if c.config.FlushTimeout <= 0 {
+		return c.kafkaProducer.Close()
+	}
+	done := make(chan error, 1)
+	go func() { done <- c.kafkaProducer.Close() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(c.config.FlushTimeout):
+		return fmt.Errorf("timed out after %s waiting for Kafka producer to flush", c.config.FlushTimeout)
+	}
 }
 
-// Send a message to Kafka.
-func (c *Component) Send(host string, payload []byte) error {
-	c.metrics.bytesSent.WithLabelValues(host).Add(float64(len(payload)))
+// Send a message to Kafka, routing it to the topic computed from
+// Configuration.TopicTemplate (or Configuration.Topic when unset). When
+// Configuration.Sync is enabled, it blocks until the broker acknowledges
+// the message and returns the resulting error, if any; otherwise, delivery
+// failures are only logged.
+func (c *Component) Send(metadata FlowMetadata, payload []byte) error {
+	host := metadata.Host
+	encoded := c.encoder.Encode(payload)
+	c.metrics.bytesSent.WithLabelValues(host).Add(float64(len(encoded)))
 	c.metrics.messagesSent.WithLabelValues(host).Inc()
-	c.kafkaProducer.Input() <- &sarama.ProducerMessage{
-		Topic: c.config.Topic,
-		Key:   sarama.StringEncoder(host),
-		Value: sarama.ByteEncoder(payload),
+
+	topic, err := c.resolveTopic(metadata)
+	if err != nil {
+		return err
 	}
-	return nil
+	if err := c.ensureTopic(topic); err != nil {
+		return err
+	}
+
+	_, _, err = c.kafkaProducer.SendMessage(&sarama.ProducerMessage{
+		Topic: topic,
+		Key:   sarama.StringEncoder(host),
+		Value: sarama.ByteEncoder(encoded),
+	})
+	return err
 }
\ No newline at end of file
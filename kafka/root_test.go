@@ -0,0 +1,111 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"akvorado/daemon"
+	"akvorado/reporter"
+)
+
+func TestSendUsesMockProducer(t *testing.T) {
+	c, messages := NewMock(t, reporter.NewMock(t), Configuration{Topic: "flows"}, Dependencies{
+		Daemon: daemon.NewMock(t),
+	})
+
+	if err := c.Send(FlowMetadata{Host: "router1"}, []byte("hello")); err != nil {
+		t.Fatalf("Send() error:\n%+v", err)
+	}
+
+	select {
+	case msg := <-messages:
+		if msg.Topic != "flows" {
+			t.Errorf("Send() topic = %q, want %q", msg.Topic, "flows")
+		}
+	default:
+		t.Error("Send() did not produce a message")
+	}
+}
+
+// TestNewFailsCleanlyAfterOpeningAdminHandles exercises New() failing after
+// the TopicTemplate and Admin ClusterAdmin/Client connections are already
+// open (setupEncoder is the last step and fails here on an unknown
+// encoding): since New() never returns a Component in that case, it alone
+// is responsible for closing them, and must do so without panicking or
+// double-closing.
+func TestNewFailsCleanlyAfterOpeningAdminHandles(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("flows", 0, broker.BrokerID()),
+	})
+
+	_, err := New(reporter.NewMock(t), Configuration{
+		Brokers:       []string{broker.Addr()},
+		Topic:         "flows",
+		TopicTemplate: "{{ .Host }}",
+		Admin:         &AdminConfiguration{},
+		Encoding:      "unknown", // forces setupEncoder() to fail after both admins are up
+	}, Dependencies{Daemon: daemon.NewMock(t)})
+	if err == nil {
+		t.Fatal("New() did not error on unknown encoding")
+	}
+}
+
+// TestNewRequiredAcksDistinguishesUnsetFromExplicitZero guards against
+// treating Configuration.RequiredAcks left unset the same as an explicit
+// AcksNone: nil must default to WaitForLocal, but a pointer to AcksNone
+// must be honored as-is, since acks=0 is a legitimate, commonly used
+// setting in its own right.
+func TestNewRequiredAcksDistinguishesUnsetFromExplicitZero(t *testing.T) {
+	acksNone := AcksNone
+	cases := []struct {
+		name         string
+		requiredAcks *RequiredAcks
+		want         sarama.RequiredAcks
+	}{
+		{"unset", nil, sarama.WaitForLocal},
+		{"explicit AcksNone", &acksNone, sarama.NoResponse},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			component, err := New(reporter.NewMock(t), Configuration{
+				Topic:        "flows",
+				RequiredAcks: c.requiredAcks,
+			}, Dependencies{Daemon: daemon.NewMock(t)})
+			if err != nil {
+				t.Fatalf("New() error:\n%+v", err)
+			}
+			if got := component.kafkaConfig.Producer.RequiredAcks; got != c.want {
+				t.Errorf("Producer.RequiredAcks = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+// TestTombWaitReturnsWithMockProducer guards against c.t.Wait() blocking
+// forever once killed when the injected producer is not *asyncProducer, as
+// is the case for the mock producer NewMock wires up by default: Start()
+// must track a goroutine on c.t regardless of producer type, or Stop()
+// deadlocks.
+func TestTombWaitReturnsWithMockProducer(t *testing.T) {
+	c, _ := NewMock(t, reporter.NewMock(t), Configuration{Topic: "flows"}, Dependencies{
+		Daemon: daemon.NewMock(t),
+	})
+
+	c.t.Kill(nil)
+	done := make(chan error, 1)
+	go func() { done <- c.t.Wait() }()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("c.t.Wait() did not return, likely never tracked a goroutine")
+	}
+}
@@ -0,0 +1,61 @@
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/Shopify/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// scramClient adapts xdg-go/scram to sarama's SCRAMClient interface.
+type scramClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return fmt.Errorf("cannot start SCRAM conversation: %w", err)
+	}
+	c.Client = client
+	c.ClientConversation = c.Client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.ClientConversation.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.ClientConversation.Done()
+}
+
+// configureSASL applies the SASL configuration to the provided sarama
+// configuration, selecting the appropriate mechanism and, for SCRAM, the
+// matching client generator.
+func configureSASL(kafkaConfig *sarama.Config, configuration SASLConfiguration) error {
+	kafkaConfig.Net.SASL.Enable = true
+	kafkaConfig.Net.SASL.User = configuration.Username
+	kafkaConfig.Net.SASL.Password = configuration.Password
+	kafkaConfig.Net.SASL.Version = sarama.SASLHandshakeV1
+
+	switch configuration.Mechanism {
+	case SASLMechanismPlain, "":
+		kafkaConfig.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case SASLMechanismSCRAMSHA256:
+		kafkaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		kafkaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{HashGeneratorFcn: scram.SHA256}
+		}
+	case SASLMechanismSCRAMSHA512:
+		kafkaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		kafkaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{HashGeneratorFcn: scram.SHA512}
+		}
+	default:
+		return fmt.Errorf("unknown SASL mechanism %q", configuration.Mechanism)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+
+	"akvorado/daemon"
+	"akvorado/reporter"
+)
+
+func TestConfigureSASL(t *testing.T) {
+	cases := []struct {
+		name      string
+		mechanism SASLMechanism
+		want      sarama.SASLMechanism
+	}{
+		{"default", "", sarama.SASLTypePlaintext},
+		{"plain", SASLMechanismPlain, sarama.SASLTypePlaintext},
+		{"scram-sha-256", SASLMechanismSCRAMSHA256, sarama.SASLTypeSCRAMSHA256},
+		{"scram-sha-512", SASLMechanismSCRAMSHA512, sarama.SASLTypeSCRAMSHA512},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kafkaConfig := sarama.NewConfig()
+			configuration := SASLConfiguration{
+				Enabled:   true,
+				Mechanism: c.mechanism,
+				Username:  "alice",
+				Password:  "secret",
+			}
+			if err := configureSASL(kafkaConfig, configuration); err != nil {
+				t.Fatalf("configureSASL() error:\n%+v", err)
+			}
+			if !kafkaConfig.Net.SASL.Enable {
+				t.Error("configureSASL() did not enable SASL")
+			}
+			if kafkaConfig.Net.SASL.Mechanism != c.want {
+				t.Errorf("configureSASL() mechanism = %q, want %q", kafkaConfig.Net.SASL.Mechanism, c.want)
+			}
+			if kafkaConfig.Net.SASL.User != "alice" || kafkaConfig.Net.SASL.Password != "secret" {
+				t.Error("configureSASL() did not set credentials")
+			}
+			isSCRAM := c.mechanism == SASLMechanismSCRAMSHA256 || c.mechanism == SASLMechanismSCRAMSHA512
+			if isSCRAM && kafkaConfig.Net.SASL.SCRAMClientGeneratorFunc == nil {
+				t.Error("configureSASL() did not set a SCRAM client generator")
+			}
+			if isSCRAM {
+				client := kafkaConfig.Net.SASL.SCRAMClientGeneratorFunc()
+				if err := client.Begin(configuration.Username, configuration.Password, ""); err != nil {
+					t.Errorf("SCRAMClient.Begin() error:\n%+v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigureSASLUnknownMechanism(t *testing.T) {
+	cases := []string{"GSSAPI", "OAUTHBEARER"}
+	for _, mechanism := range cases {
+		t.Run(mechanism, func(t *testing.T) {
+			kafkaConfig := sarama.NewConfig()
+			configuration := SASLConfiguration{Enabled: true, Mechanism: SASLMechanism(mechanism)}
+			if err := configureSASL(kafkaConfig, configuration); err == nil {
+				t.Errorf("configureSASL() did not error on mechanism %q", mechanism)
+			}
+		})
+	}
+}
+
+// TestNewWithSASLPlainAgainstMockBroker exercises configureSASL end-to-end
+// through New(), against a mock broker that actually runs the SASL/PLAIN
+// handshake, rather than only unit-testing configureSASL() against a bare
+// sarama.Config as TestConfigureSASL does.
+func TestNewWithSASLPlainAgainstMockBroker(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("flows", 0, broker.BrokerID()),
+		"SaslHandshakeRequest":    sarama.NewMockSaslHandshakeResponse(t).SetEnabledMechanisms([]string{string(sarama.SASLTypePlaintext)}),
+		"SaslAuthenticateRequest": sarama.NewMockSaslAuthenticateResponse(t),
+	})
+
+	c, err := New(reporter.NewMock(t), Configuration{
+		Brokers: []string{broker.Addr()},
+		Topic:   "flows",
+		SASL: SASLConfiguration{
+			Enabled:  true,
+			Username: "alice",
+			Password: "secret",
+		},
+	}, Dependencies{Daemon: daemon.NewMock(t)})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatalf("Start() error:\n%+v", err)
+	}
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop() error:\n%+v", err)
+	}
+}
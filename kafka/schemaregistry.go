@@ -0,0 +1,64 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// schemaRegistrySubjectSuffix is appended to the topic name to build the
+// subject a schema is registered under, following the TopicNameStrategy
+// used by the Confluent schema registry.
+const schemaRegistrySubjectSuffix = "-value"
+
+// schemaRegistryClient registers schemas with a Confluent-compatible
+// schema registry.
+type schemaRegistryClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func newSchemaRegistryClient(configuration SchemaRegistryConfiguration) *schemaRegistryClient {
+	timeout := configuration.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &schemaRegistryClient{
+		baseURL:    configuration.URL,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+type registerSchemaRequest struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+type registerSchemaResponse struct {
+	ID int32 `json:"id"`
+}
+
+// register registers schema under the subject derived from topic and
+// returns the schema ID assigned by the registry.
+func (c *schemaRegistryClient) register(topic, schema, schemaType string) (int32, error) {
+	body, err := json.Marshal(registerSchemaRequest{Schema: schema, SchemaType: schemaType})
+	if err != nil {
+		return 0, fmt.Errorf("cannot build schema registration request: %w", err)
+	}
+	url := fmt.Sprintf("%s/subjects/%s%s/versions", c.baseURL, topic, schemaRegistrySubjectSuffix)
+	resp, err := c.httpClient.Post(url, "application/vnd.schemaregistry.v1+json", bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("cannot reach schema registry: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("schema registry returned status %d", resp.StatusCode)
+	}
+	var out registerSchemaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, fmt.Errorf("cannot decode schema registry response: %w", err)
+	}
+	return out.ID, nil
+}
@@ -0,0 +1,190 @@
+//go:build !release
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"akvorado/reporter"
+)
+
+// mockMessagesBufferSize bounds how many produced messages NewMock buffers
+// on the channel it returns, so tests reading it lazily don't block Send().
+const mockMessagesBufferSize = 1000
+
+// mockAsyncProducer implements kafkaProducer on top of sarama/mocks, while
+// also pushing every produced message onto a channel so tests can assert
+// on what was sent.
+type mockAsyncProducer struct {
+	mock     *mocks.AsyncProducer
+	messages chan *sarama.ProducerMessage
+}
+
+func (p *mockAsyncProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	// Register the expectation for this message right before sending it:
+	// sarama/mocks requires expectations to be registered ahead of time,
+	// and pre-registering a fixed number up front would either run out
+	// for a test sending more messages, or leave unconsumed expectations
+	// behind for Close() to complain about.
+	p.mock.ExpectInputAndSucceed()
+	p.mock.Input() <- msg
+	select {
+	case errMsg := <-p.mock.Errors():
+		return 0, 0, errMsg.Err
+	case <-p.mock.Successes():
+		p.messages <- msg
+		return 0, 0, nil
+	}
+}
+
+func (p *mockAsyncProducer) Close() error {
+	close(p.messages)
+	return p.mock.Close()
+}
+
+// NewMock creates a new Kafka component for tests. It is already started.
+//
+// By default, it injects a mock producer backed by sarama/mocks and
+// returns a channel over which produced messages can be asserted.
+//
+// When the AKVORADO_KAFKA_INTEGRATION environment variable is set to "1",
+// it instead spins up a real single-broker Kafka cluster through
+// testcontainers-go, points configuration.Brokers at it, and uses the real
+// producer; the returned channel is then nil, as there is no way to
+// intercept messages without consuming them from the topic.
+func NewMock(t *testing.T, reporter *reporter.Reporter, configuration Configuration, dependencies Dependencies) (*Component, <-chan *sarama.ProducerMessage) {
+	t.Helper()
+
+	if os.Getenv("AKVORADO_KAFKA_INTEGRATION") == "1" {
+		configuration.Brokers = startMockKafkaContainer(t)
+		c, err := New(reporter, configuration, dependencies)
+		if err != nil {
+			t.Fatalf("New() error:\n%+v", err)
+		}
+		if err := c.Start(); err != nil {
+			t.Fatalf("Start() error:\n%+v", err)
+		}
+		t.Cleanup(func() {
+			if err := c.Stop(); err != nil {
+				t.Logf("Stop() error:\n%+v", err)
+			}
+		})
+		return c, nil
+	}
+
+	c, err := New(reporter, configuration, dependencies)
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	// The mock producer needs to see both successes and errors to know
+	// how to answer SendMessage().
+	c.kafkaConfig.Producer.Return.Successes = true
+	c.kafkaConfig.Producer.Return.Errors = true
+
+	mock := mocks.NewAsyncProducer(t, c.kafkaConfig)
+	messages := make(chan *sarama.ProducerMessage, mockMessagesBufferSize)
+	c.createKafkaProducer = func() (kafkaProducer, error) {
+		return &mockAsyncProducer{mock: mock, messages: messages}, nil
+	}
+
+	if err := c.Start(); err != nil {
+		t.Fatalf("Start() error:\n%+v", err)
+	}
+	t.Cleanup(func() {
+		if err := c.Stop(); err != nil {
+			t.Logf("Stop() error:\n%+v", err)
+		}
+	})
+	return c, messages
+}
+
+// zookeeperNetworkAlias is the hostname the Kafka container reaches the
+// Zookeeper container under, on the private network shared between them.
+const zookeeperNetworkAlias = "zookeeper"
+
+// startMockKafkaContainer starts a single-broker Kafka cluster, backed by a
+// Zookeeper container on a private network, using testcontainers-go, and
+// returns the Kafka broker's bootstrap address. The containers and network
+// are torn down when the test completes.
+func startMockKafkaContainer(t *testing.T) []string {
+	t.Helper()
+	ctx := context.Background()
+
+	networkName := fmt.Sprintf("akvorado-kafka-test-%d", time.Now().UnixNano())
+	network, err := testcontainers.GenericNetwork(ctx, testcontainers.GenericNetworkRequest{
+		NetworkRequest: testcontainers.NetworkRequest{Name: networkName, CheckDuplicate: true},
+	})
+	if err != nil {
+		t.Fatalf("cannot create Docker network:\n%+v", err)
+	}
+	t.Cleanup(func() {
+		if err := network.Remove(ctx); err != nil {
+			t.Logf("cannot remove Docker network:\n%+v", err)
+		}
+	})
+
+	zookeeper, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:          "confluentinc/cp-zookeeper:7.2.1",
+			ExposedPorts:   []string{"2181/tcp"},
+			Networks:       []string{networkName},
+			NetworkAliases: map[string][]string{networkName: {zookeeperNetworkAlias}},
+			Env: map[string]string{
+				"ZOOKEEPER_CLIENT_PORT": "2181",
+			},
+			WaitingFor: wait.ForListeningPort("2181/tcp").WithStartupTimeout(2 * time.Minute),
+		},
+		Started: true,
+	})
+	if err != nil {
+		t.Fatalf("cannot start Zookeeper container:\n%+v", err)
+	}
+	t.Cleanup(func() {
+		if err := zookeeper.Terminate(ctx); err != nil {
+			t.Logf("cannot terminate Zookeeper container:\n%+v", err)
+		}
+	})
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "confluentinc/cp-kafka:7.2.1",
+			ExposedPorts: []string{"9092/tcp"},
+			Networks:     []string{networkName},
+			Env: map[string]string{
+				"KAFKA_ZOOKEEPER_CONNECT":                zookeeperNetworkAlias + ":2181",
+				"KAFKA_LISTENERS":                        "PLAINTEXT://0.0.0.0:9092",
+				"KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR": "1",
+				"KAFKA_AUTO_CREATE_TOPICS_ENABLE":        "true",
+			},
+			WaitingFor: wait.ForListeningPort("9092/tcp").WithStartupTimeout(2 * time.Minute),
+		},
+		Started: true,
+	})
+	if err != nil {
+		t.Fatalf("cannot start Kafka container:\n%+v", err)
+	}
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Logf("cannot terminate Kafka container:\n%+v", err)
+		}
+	})
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatalf("cannot get Kafka container host:\n%+v", err)
+	}
+	port, err := container.MappedPort(ctx, "9092/tcp")
+	if err != nil {
+		t.Fatalf("cannot get Kafka container port:\n%+v", err)
+	}
+	return []string{fmt.Sprintf("%s:%s", host, port.Port())}
+}
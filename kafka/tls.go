@@ -0,0 +1,40 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// buildTLSConfig builds a *tls.Config from the system root CAs plus the
+// optional custom CA and client certificate described in configuration.
+func buildTLSConfig(configuration TLSConfiguration) (*tls.Config, error) {
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load system CA pool: %w", err)
+	}
+	if configuration.CACertFile != "" {
+		pemBytes, err := os.ReadFile(configuration.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA certificate %q: %w", configuration.CACertFile, err)
+		}
+		if ok := rootCAs.AppendCertsFromPEM(pemBytes); !ok {
+			return nil, fmt.Errorf("cannot parse CA certificate %q", configuration.CACertFile)
+		}
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:            rootCAs,
+		InsecureSkipVerify: configuration.SkipVerify,
+		ServerName:         configuration.ServerName,
+	}
+	if configuration.ClientCertFile != "" || configuration.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(configuration.ClientCertFile, configuration.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
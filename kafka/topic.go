@@ -0,0 +1,138 @@
+package kafka
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// topicRefreshInterval is how often the topicManager refreshes its view of
+// the topics known to exist on the brokers.
+const topicRefreshInterval = 10 * time.Minute
+
+// topicHashBuckets is the number of buckets KeyHashBucket is computed over,
+// in TopicTemplate.
+const topicHashBuckets = 10
+
+// FlowMetadata carries the per-flow attributes a TopicTemplate can use to
+// route a message to a specific topic.
+type FlowMetadata struct {
+	// Host is the exporter address the flow was received from.
+	Host string
+	// SamplerAddress is the address of the sampler that generated the flow.
+	SamplerAddress string
+	// VRF is the VRF the flow was seen in, if any.
+	VRF string
+	// Protocol is the IP protocol of the flow (e.g. "tcp", "udp").
+	Protocol string
+	// InIfIndex is the SNMP index of the flow's ingress interface.
+	InIfIndex uint
+}
+
+// topicData is the value TopicTemplate is executed against.
+type topicData struct {
+	FlowMetadata
+	// KeyHashBucket is the hash bucket, in [0, topicHashBuckets), of the
+	// Kafka message key that will be used for this flow.
+	KeyHashBucket int
+}
+
+// keyHashBucket returns the hash bucket a message key falls into.
+func keyHashBucket(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % topicHashBuckets)
+}
+
+// resolveTopic evaluates the configured topic template (if any) for the
+// given flow metadata, falling back to Configuration.Topic.
+func (c *Component) resolveTopic(metadata FlowMetadata) (string, error) {
+	if c.topicTemplate == nil {
+		return c.config.Topic, nil
+	}
+	var buf bytes.Buffer
+	data := topicData{FlowMetadata: metadata, KeyHashBucket: keyHashBucket(metadata.Host)}
+	if err := c.topicTemplate.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("cannot evaluate topic template: %w", err)
+	}
+	if topic := buf.String(); topic != "" {
+		return topic, nil
+	}
+	return c.config.TopicDefault, nil
+}
+
+// ensureTopic makes sure topic is created, when a topicManager is in use.
+func (c *Component) ensureTopic(topic string) error {
+	if c.topics == nil {
+		return nil
+	}
+	return c.topics.ensure(topic)
+}
+
+// topicManager tracks the set of topics known to exist on the brokers and,
+// when configured, creates missing ones referenced by a topic template.
+type topicManager struct {
+	admin  sarama.ClusterAdmin
+	config *AutoCreateTopicsConfiguration
+
+	mu    sync.RWMutex
+	known map[string]bool
+}
+
+func newTopicManager(admin sarama.ClusterAdmin, config *AutoCreateTopicsConfiguration) *topicManager {
+	return &topicManager{
+		admin:  admin,
+		config: config,
+		known:  map[string]bool{},
+	}
+}
+
+// refresh reloads the list of topics known to exist on the brokers.
+func (m *topicManager) refresh() error {
+	topics, err := m.admin.ListTopics()
+	if err != nil {
+		return fmt.Errorf("cannot list topics: %w", err)
+	}
+	known := make(map[string]bool, len(topics))
+	for topic := range topics {
+		known[topic] = true
+	}
+	m.mu.Lock()
+	m.known = known
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *topicManager) exists(topic string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.known[topic]
+}
+
+// ensure creates topic if it is not known to exist yet and auto-creation is
+// configured.
+func (m *topicManager) ensure(topic string) error {
+	if m.exists(topic) {
+		return nil
+	}
+	if m.config == nil {
+		return nil
+	}
+	err := m.admin.CreateTopic(topic, &sarama.TopicDetail{
+		NumPartitions:     m.config.Partitions,
+		ReplicationFactor: m.config.ReplicationFactor,
+		ConfigEntries:     m.config.ConfigEntries,
+	}, false)
+	if err != nil && !errors.Is(err, sarama.ErrTopicAlreadyExists) {
+		return fmt.Errorf("cannot create topic %q: %w", topic, err)
+	}
+	m.mu.Lock()
+	m.known[topic] = true
+	m.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,90 @@
+package kafka
+
+import (
+	"testing"
+	"text/template"
+)
+
+func TestResolveTopic(t *testing.T) {
+	cases := []struct {
+		name     string
+		template string
+		metadata FlowMetadata
+		want     string
+	}{
+		{
+			name:     "no template",
+			template: "",
+			metadata: FlowMetadata{Host: "router1"},
+			want:     "flows-default",
+		},
+		{
+			name:     "per-host topic",
+			template: "flows-{{ .Host }}",
+			metadata: FlowMetadata{Host: "router1"},
+			want:     "flows-router1",
+		},
+		{
+			name:     "per-vrf topic",
+			template: "{{ if .VRF }}flows-{{ .VRF }}{{ end }}",
+			metadata: FlowMetadata{Host: "router1", VRF: "customer-a"},
+			want:     "flows-customer-a",
+		},
+		{
+			name:     "falls back to default when template yields empty string",
+			template: "{{ if .VRF }}flows-{{ .VRF }}{{ end }}",
+			metadata: FlowMetadata{Host: "router1"},
+			want:     "flows-default",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			comp := &Component{config: Configuration{Topic: "flows-default", TopicDefault: "flows-default"}}
+			if c.template != "" {
+				tmpl, err := template.New("topic").Parse(c.template)
+				if err != nil {
+					t.Fatalf("Parse() error:\n%+v", err)
+				}
+				comp.topicTemplate = tmpl
+			}
+			got, err := comp.resolveTopic(c.metadata)
+			if err != nil {
+				t.Fatalf("resolveTopic() error:\n%+v", err)
+			}
+			if got != c.want {
+				t.Errorf("resolveTopic() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestKeyHashBucketIsStableAndBounded(t *testing.T) {
+	b1 := keyHashBucket("router1")
+	b2 := keyHashBucket("router1")
+	if b1 != b2 {
+		t.Errorf("keyHashBucket() is not stable: %d != %d", b1, b2)
+	}
+	if b1 < 0 || b1 >= topicHashBuckets {
+		t.Errorf("keyHashBucket() = %d, out of range [0, %d)", b1, topicHashBuckets)
+	}
+}
+
+func TestTopicManagerEnsureCreatesMissingTopic(t *testing.T) {
+	admin := &fakeClusterAdmin{topics: map[string]bool{"existing": true}}
+	m := newTopicManager(admin, &AutoCreateTopicsConfiguration{Partitions: 3, ReplicationFactor: 2})
+	if err := m.refresh(); err != nil {
+		t.Fatalf("refresh() error:\n%+v", err)
+	}
+	if !m.exists("existing") {
+		t.Error("refresh() did not pick up the existing topic")
+	}
+	if err := m.ensure("new-topic"); err != nil {
+		t.Fatalf("ensure() error:\n%+v", err)
+	}
+	if !admin.created["new-topic"] {
+		t.Error("ensure() did not create the missing topic")
+	}
+	if !m.exists("new-topic") {
+		t.Error("ensure() did not mark the topic as known")
+	}
+}
@@ -0,0 +1,15 @@
+package promkafka
+
+// Configuration describes the configuration for the promkafka component.
+type Configuration struct {
+	// Path is the HTTP path the remote-write endpoint is mounted on.
+	Path string
+	// MaxSamplesPerMessage batches samples into Kafka messages of at most
+	// this many samples.
+	MaxSamplesPerMessage int
+	// KeyTemplate is a Go text/template evaluated for each sample to
+	// compute the Kafka message key (and, through the kafka component's
+	// own topic template, the destination topic). It is executed against
+	// a Sample. Leave empty to key messages by metric name.
+	KeyTemplate string
+}
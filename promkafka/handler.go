@@ -0,0 +1,151 @@
+package promkafka
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+
+	"akvorado/kafka"
+)
+
+// Sample is the decoded representation of a single Prometheus remote-write
+// sample, ready to be forwarded to Kafka.
+type Sample struct {
+	Metric    string            `json:"metric"`
+	Labels    map[string]string `json:"labels"`
+	Value     float64           `json:"value"`
+	Timestamp int64             `json:"timestamp"`
+}
+
+// remoteWriteHandler decodes a Prometheus remote_write request and
+// forwards its samples to Kafka.
+func (c *Component) remoteWriteHandler(w http.ResponseWriter, r *http.Request) {
+	compressed, err := io.ReadAll(r.Body)
+	if err != nil {
+		c.r.Err(err).Msg("cannot read remote-write request body")
+		http.Error(w, "cannot read request body", http.StatusBadRequest)
+		return
+	}
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		c.r.Err(err).Msg("cannot decompress remote-write request")
+		http.Error(w, "cannot decompress request", http.StatusBadRequest)
+		return
+	}
+	var writeRequest prompb.WriteRequest
+	if err := proto.Unmarshal(data, &writeRequest); err != nil {
+		c.r.Err(err).Msg("cannot decode remote-write request")
+		http.Error(w, "cannot decode request", http.StatusBadRequest)
+		return
+	}
+
+	samples := decodeSamples(writeRequest.Timeseries)
+	c.metrics.samplesReceived.Add(float64(len(samples)))
+
+	for _, batch := range c.batchSamples(samples) {
+		if err := c.forwardBatch(batch); err != nil {
+			c.metrics.samplesFailed.Add(float64(len(batch)))
+			c.r.Err(err).Msg("cannot forward samples to Kafka")
+			continue
+		}
+		c.metrics.samplesForwarded.Add(float64(len(batch)))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeSamples flattens a slice of Prometheus time series into
+// individual samples.
+func decodeSamples(series []prompb.TimeSeries) []Sample {
+	samples := make([]Sample, 0, len(series))
+	for _, ts := range series {
+		labels := make(map[string]string, len(ts.Labels))
+		metric := ""
+		for _, l := range ts.Labels {
+			labels[l.Name] = l.Value
+			if l.Name == "__name__" {
+				metric = l.Value
+			}
+		}
+		for _, s := range ts.Samples {
+			samples = append(samples, Sample{
+				Metric:    metric,
+				Labels:    labels,
+				Value:     s.Value,
+				Timestamp: s.Timestamp,
+			})
+		}
+	}
+	return samples
+}
+
+// batchSamples groups samples by destination key, then splits each group
+// into batches of at most Configuration.MaxSamplesPerMessage samples.
+func (c *Component) batchSamples(samples []Sample) [][]Sample {
+	byKey := map[string][]Sample{}
+	var order []string
+	for _, s := range samples {
+		key, err := c.resolveKey(s)
+		if err != nil {
+			c.r.Err(err).Str("metric", s.Metric).Msg("cannot resolve Kafka key for sample")
+			continue
+		}
+		if _, ok := byKey[key]; !ok {
+			order = append(order, key)
+		}
+		byKey[key] = append(byKey[key], s)
+	}
+
+	maxPerMessage := c.config.MaxSamplesPerMessage
+
+	var batches [][]Sample
+	for _, key := range order {
+		group := byKey[key]
+		for len(group) > 0 {
+			n := len(group)
+			if maxPerMessage > 0 && maxPerMessage < n {
+				n = maxPerMessage
+			}
+			batches = append(batches, group[:n])
+			group = group[n:]
+		}
+	}
+	return batches
+}
+
+// resolveKey computes the Kafka message key for a sample, using
+// Configuration.KeyTemplate when set, or the metric name otherwise.
+func (c *Component) resolveKey(s Sample) (string, error) {
+	if c.keyTemplate == nil {
+		return s.Metric, nil
+	}
+	var buf bytes.Buffer
+	if err := c.keyTemplate.Execute(&buf, s); err != nil {
+		return "", fmt.Errorf("cannot evaluate key template: %w", err)
+	}
+	if key := buf.String(); key != "" {
+		return key, nil
+	}
+	return s.Metric, nil
+}
+
+// forwardBatch serializes a batch of samples and sends it to Kafka.
+func (c *Component) forwardBatch(batch []Sample) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("cannot marshal samples: %w", err)
+	}
+	key, err := c.resolveKey(batch[0])
+	if err != nil {
+		return err
+	}
+	return c.d.Kafka.Send(kafka.FlowMetadata{Host: key}, payload)
+}
@@ -0,0 +1,144 @@
+package promkafka
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+
+	"akvorado/daemon"
+	"akvorado/kafka"
+	"akvorado/reporter"
+)
+
+// newTestComponent creates a promkafka component wired to a mocked Kafka
+// component, so tests can assert on the messages actually produced.
+func newTestComponent(t *testing.T, configuration Configuration) (*Component, <-chan *kafkaMessage) {
+	t.Helper()
+
+	r := reporter.NewMock(t)
+	kafkaComponent, produced := kafka.NewMock(t, r, kafka.Configuration{Topic: "metrics"}, kafka.Dependencies{
+		Daemon: daemon.NewMock(t),
+	})
+
+	c, err := New(r, configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Kafka:  kafkaComponent,
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+
+	messages := make(chan *kafkaMessage, 100)
+	go func() {
+		for msg := range produced {
+			value, err := msg.Value.Encode()
+			if err != nil {
+				t.Errorf("cannot encode produced message: %+v", err)
+				continue
+			}
+			key, err := msg.Key.Encode()
+			if err != nil {
+				t.Errorf("cannot encode produced message key: %+v", err)
+				continue
+			}
+			messages <- &kafkaMessage{key: string(key), value: value}
+		}
+		close(messages)
+	}()
+
+	return c, messages
+}
+
+// kafkaMessage is a decoded view of a message produced to the mocked Kafka
+// component, used to assert on what remoteWriteHandler forwarded.
+type kafkaMessage struct {
+	key   string
+	value []byte
+}
+
+// writeRequestBody snappy-compresses and marshals a Prometheus
+// remote_write request, as sent by a real Prometheus instance.
+func writeRequestBody(t *testing.T, series []prompb.TimeSeries) []byte {
+	t.Helper()
+	encoded, err := proto.Marshal(&prompb.WriteRequest{Timeseries: series})
+	if err != nil {
+		t.Fatalf("cannot marshal WriteRequest: %+v", err)
+	}
+	return snappy.Encode(nil, encoded)
+}
+
+func sampleSeries(metric string, value float64, timestamp int64) prompb.TimeSeries {
+	return prompb.TimeSeries{
+		Labels:  []prompb.Label{{Name: "__name__", Value: metric}},
+		Samples: []prompb.Sample{{Value: value, Timestamp: timestamp}},
+	}
+}
+
+func TestRemoteWriteHandlerDecodesAndForwards(t *testing.T) {
+	c, messages := newTestComponent(t, Configuration{Path: "/receive"})
+
+	body := writeRequestBody(t, []prompb.TimeSeries{sampleSeries("up", 1, 1000)})
+	req := httptest.NewRequest("POST", "/receive", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	c.remoteWriteHandler(w, req)
+
+	if w.Code != 204 {
+		t.Fatalf("remoteWriteHandler() status = %d, want 204", w.Code)
+	}
+
+	msg, ok := <-messages
+	if !ok {
+		t.Fatal("remoteWriteHandler() did not forward any message to Kafka")
+	}
+	if msg.key != "up" {
+		t.Errorf("Kafka message key = %q, want %q", msg.key, "up")
+	}
+	var got []Sample
+	if err := json.Unmarshal(msg.value, &got); err != nil {
+		t.Fatalf("cannot decode forwarded payload: %+v", err)
+	}
+	if len(got) != 1 || got[0].Metric != "up" || got[0].Value != 1 {
+		t.Errorf("forwarded samples = %+v, want a single \"up\" sample with value 1", got)
+	}
+}
+
+func TestRemoteWriteHandlerSplitsBatchesByMaxSamplesPerMessage(t *testing.T) {
+	c, messages := newTestComponent(t, Configuration{Path: "/receive", MaxSamplesPerMessage: 2})
+
+	series := make([]prompb.TimeSeries, 0, 5)
+	for i := 0; i < 5; i++ {
+		series = append(series, sampleSeries("up", float64(i), int64(i)*1000))
+	}
+	body := writeRequestBody(t, series)
+	req := httptest.NewRequest("POST", "/receive", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	c.remoteWriteHandler(w, req)
+
+	if w.Code != 204 {
+		t.Fatalf("remoteWriteHandler() status = %d, want 204", w.Code)
+	}
+
+	var total int
+	for i := 0; i < 3; i++ {
+		msg, ok := <-messages
+		if !ok {
+			t.Fatalf("remoteWriteHandler() forwarded only %d batches, want 3", i)
+		}
+		var got []Sample
+		if err := json.Unmarshal(msg.value, &got); err != nil {
+			t.Fatalf("cannot decode forwarded payload: %+v", err)
+		}
+		if len(got) > 2 {
+			t.Errorf("batch %d has %d samples, want at most 2", i, len(got))
+		}
+		total += len(got)
+	}
+	if total != 5 {
+		t.Errorf("forwarded %d samples across batches, want 5", total)
+	}
+}
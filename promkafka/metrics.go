@@ -0,0 +1,35 @@
+package promkafka
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metrics holds the various metrics exported by the promkafka component.
+type metrics struct {
+	samplesReceived  prometheus.Counter
+	samplesForwarded prometheus.Counter
+	samplesFailed    prometheus.Counter
+}
+
+// initMetrics initializes the metrics for the promkafka component.
+func (c *Component) initMetrics() {
+	c.metrics.samplesReceived = promauto.With(c.r).NewCounter(
+		prometheus.CounterOpts{
+			Name: "samples_received",
+			Help: "Number of samples received through remote write.",
+		},
+	)
+	c.metrics.samplesForwarded = promauto.With(c.r).NewCounter(
+		prometheus.CounterOpts{
+			Name: "samples_forwarded",
+			Help: "Number of samples forwarded to Kafka.",
+		},
+	)
+	c.metrics.samplesFailed = promauto.With(c.r).NewCounter(
+		prometheus.CounterOpts{
+			Name: "samples_failed",
+			Help: "Number of samples that could not be forwarded to Kafka.",
+		},
+	)
+}
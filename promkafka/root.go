@@ -0,0 +1,63 @@
+// Package promkafka accepts Prometheus remote_write requests and
+// republishes the received samples to Kafka.
+package promkafka
+
+import (
+	"fmt"
+	"net/http"
+	"text/template"
+
+	"akvorado/daemon"
+	akvhttp "akvorado/http"
+	"akvorado/kafka"
+	"akvorado/reporter"
+)
+
+// Component represents the promkafka exporter.
+type Component struct {
+	r      *reporter.Reporter
+	d      *Dependencies
+	config Configuration
+
+	keyTemplate *template.Template
+	metrics     metrics
+}
+
+// Dependencies define the dependencies of the promkafka component.
+type Dependencies struct {
+	Daemon daemon.Component
+	HTTP   akvhttp.Component
+	Kafka  *kafka.Component
+}
+
+// New creates a new promkafka component.
+func New(reporter *reporter.Reporter, configuration Configuration, dependencies Dependencies) (*Component, error) {
+	var keyTemplate *template.Template
+	if configuration.KeyTemplate != "" {
+		tmpl, err := template.New("key").Parse(configuration.KeyTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse key template: %w", err)
+		}
+		keyTemplate = tmpl
+	}
+
+	c := Component{
+		r:           reporter,
+		d:           &dependencies,
+		config:      configuration,
+		keyTemplate: keyTemplate,
+	}
+	c.initMetrics()
+	return &c, nil
+}
+
+// Start starts the promkafka component, mounting its HTTP handler.
+func (c *Component) Start() error {
+	c.d.HTTP.AddHandler(c.config.Path, http.HandlerFunc(c.remoteWriteHandler))
+	return nil
+}
+
+// Stop stops the promkafka component.
+func (c *Component) Stop() error {
+	return nil
+}